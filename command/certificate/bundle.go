@@ -0,0 +1,130 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/errs"
+	"github.com/urfave/cli"
+)
+
+func bundleCommand() cli.Command {
+	return cli.Command{
+		Name:      "bundle",
+		Action:    cli.ActionFunc(bundleAction),
+		Usage:     `bundle a certificate with its intermediate certificate(s).`,
+		UsageText: `step certificate bundle CRT_FILE INTERMEDIATE_CRT_FILE BUNDLE_FILE`,
+		Description: `**step certificate bundle** reads a leaf certificate and one or more
+intermediate certificates and writes a single PEM file, ordered leaf-first,
+suitable for presentation by a TLS server. Each certificate in the chain is
+checked to make sure its 'AuthorityKeyId' matches the 'SubjectKeyId' of the
+next certificate; bundling fails if a link is missing.
+
+  POSITIONAL ARGUMENTS
+    CRT_FILE
+      The path to the leaf certificate to bundle.
+
+    INTERMEDIATE_CRT_FILE
+      The path to a PEM file containing one or more intermediate
+      certificates, ordered from the leaf's issuer to the root.
+
+    BUNDLE_FILE
+      File to write the bundle to (PEM format).`,
+	}
+}
+
+func bundleAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 3); err != nil {
+		return err
+	}
+
+	crtFile := ctx.Args().Get(0)
+	intermediateFile := ctx.Args().Get(1)
+	bundleFile := ctx.Args().Get(2)
+
+	leaf, leafBlock, err := readCertificate(crtFile)
+	if err != nil {
+		return err
+	}
+	intermediates, intermediateBlocks, err := readCertificates(intermediateFile)
+	if err != nil {
+		return err
+	}
+	if len(intermediates) == 0 {
+		return errors.Errorf("'%s' contains no certificates", intermediateFile)
+	}
+
+	chain := append([]*x509.Certificate{leaf}, intermediates...)
+	for i := 0; i < len(chain)-1; i++ {
+		if !bytes.Equal(chain[i].AuthorityKeyId, chain[i+1].SubjectKeyId) {
+			return errors.Errorf("certificate '%s' does not chain to the next certificate in '%s': "+
+				"AuthorityKeyId of '%s' does not match SubjectKeyId of '%s'",
+				crtFile, intermediateFile, chain[i].Subject.CommonName, chain[i+1].Subject.CommonName)
+		}
+	}
+
+	blocks := append([]*pem.Block{leafBlock}, intermediateBlocks...)
+	var out []byte
+	for _, block := range blocks {
+		out = append(out, pem.EncodeToMemory(block)...)
+	}
+
+	if err := ioutil.WriteFile(bundleFile, out, os.FileMode(0600)); err != nil {
+		return errs.FileError(err, bundleFile)
+	}
+	return nil
+}
+
+// readCertificate reads and parses the first CERTIFICATE PEM block in path.
+func readCertificate(path string) (*x509.Certificate, *pem.Block, error) {
+	certs, blocks, err := readCertificates(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(certs) == 0 {
+		return nil, nil, errors.Errorf("'%s' contains no certificates", path)
+	}
+	return certs[0], blocks[0], nil
+}
+
+// readCertificates reads and parses every CERTIFICATE PEM block in path, in order.
+func readCertificates(path string) ([]*x509.Certificate, []*pem.Block, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, errs.FileError(err, path)
+	}
+	certs, blocks, err := parseCertificatesPEM(b)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse certificate in '%s'", path)
+	}
+	return certs, blocks, nil
+}
+
+// parseCertificatesPEM parses every CERTIFICATE PEM block in b, in order.
+func parseCertificatesPEM(b []byte) ([]*x509.Certificate, []*pem.Block, error) {
+	var (
+		certs  []*x509.Certificate
+		blocks []*pem.Block
+		block  *pem.Block
+	)
+	for len(b) > 0 {
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		certs = append(certs, crt)
+		blocks = append(blocks, block)
+	}
+	return certs, blocks, nil
+}
@@ -1,6 +1,7 @@
 package certificate
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -9,10 +10,12 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	stepx509 "github.com/smallstep/cli/crypto/certificates/x509"
 	"github.com/smallstep/cli/crypto/keys"
+	"github.com/smallstep/cli/crypto/kms"
 	"github.com/smallstep/cli/errs"
 	"github.com/smallstep/cli/utils/reader"
 	"github.com/urfave/cli"
@@ -71,7 +74,15 @@ This command returns 0 on success and \>0 if any error occurs.
     :  Generate a certificate that can be used to sign additional leaf or intermediate certificates.
 
     **root-ca**
-    :  Generate a new self-signed root certificate suitable for use as a root CA.`,
+    :  Generate a new self-signed root certificate suitable for use as a root CA.
+
+  When **--type**=ssh, <profile> instead selects the SSH certificate type and
+  must be one of:
+    **user**
+    :  Generate an SSH certificate for authenticating users.
+
+    **host**
+    :  Generate an SSH certificate for authenticating hosts.`,
 			},
 			cli.BoolFlag{
 				Name:  "csr",
@@ -85,6 +96,23 @@ This command returns 0 on success and \>0 if any error occurs.
 				Name:  "ca-key",
 				Usage: `The certificate authority private key used to sign the new certificate (PEM file).`,
 			},
+			cli.StringFlag{
+				Name: "ca-kms",
+				Usage: `Locate the certificate authority's private key in a hardware or cloud
+key management service instead of reading it from **--ca-key**. <uri> is a
+KMS URI following the RFC 7512 PKCS #11 URI form, e.g.
+'pkcs11:module-path=/usr/lib/libykcs11.so;token=YubiKey#1' or
+'yubihsm:slot-id=1'. Mutually exclusive with **--ca-key**.`,
+			},
+			cli.StringFlag{
+				Name: "kms",
+				Usage: `Generate the certificate or CSR subject's key inside a hardware or
+cloud key management service instead of as a software key. <uri> is a KMS
+URI in the same form as **--ca-kms**. KEY_FILE receives a reference URI
+rather than key material. Combine with **--ca-kms** to keep a CA's signing
+key on hardware while generating an ordinary hardware-backed subject key,
+or use **--ca-key** to sign with a software CA key instead.`,
+			},
 			cli.BoolFlag{
 				Name: "no-password",
 				Usage: `Do not ask for a password to encrypt the private key.
@@ -95,6 +123,106 @@ recommended. Requires **--insecure** flag.`,
 				Name:   "insecure",
 				Hidden: true,
 			},
+			cli.StringFlag{
+				Name: "principals",
+				Usage: `Comma-separated list of principals (user or host names) for the
+'ssh' certificate type. Maps to the certificate's ValidPrincipals.`,
+			},
+			cli.StringFlag{
+				Name: "not-before",
+				Usage: `The time that the certificate becomes valid. <not-before> can be a
+date in RFC3339 format, or a duration such as "8760h" meaning the
+certificate becomes valid 8760 hours from now. Default is now.`,
+			},
+			cli.StringFlag{
+				Name: "not-after",
+				Usage: `The time that the certificate becomes invalid. <not-after> can be
+a date in RFC3339 format, or a duration such as "8760h" meaning the
+certificate expires 8760 hours from **--not-before**. Default depends on
+the certificate profile.`,
+			},
+			cli.StringFlag{
+				Name:  "key-id",
+				Usage: `The key identifier for the 'ssh' certificate type. Maps to KeyId.`,
+			},
+			cli.StringSliceFlag{
+				Name: "extension",
+				Usage: `A 'key=value' extension to add to the 'ssh' certificate. Repeat the
+flag to add more than one. Maps to Extensions.`,
+			},
+			cli.StringSliceFlag{
+				Name: "critical-option",
+				Usage: `A 'key=value' critical option to add to the 'ssh' certificate. Repeat
+the flag to add more than one. Maps to CriticalOptions.`,
+			},
+			cli.StringSliceFlag{
+				Name:  "dns",
+				Usage: `A DNS name to add as a Subject Alternative Name. Repeat the flag to add more than one.`,
+			},
+			cli.StringSliceFlag{
+				Name:  "ip",
+				Usage: `An IP address to add as a Subject Alternative Name. Repeat the flag to add more than one.`,
+			},
+			cli.StringFlag{
+				Name:  "email",
+				Usage: `An email address to add as a Subject Alternative Name.`,
+			},
+			cli.StringFlag{
+				Name:  "uri",
+				Usage: `A URI to add as a Subject Alternative Name.`,
+			},
+			cli.StringFlag{
+				Name: "key-usage",
+				Usage: `Comma-separated list of key usages to set on the certificate.
+
+: <key-usage> is a case-sensitive string and must be a comma-separated
+list of one or more of:
+    **digitalSignature**, **contentCommitment**, **keyEncipherment**,
+    **dataEncipherment**, **keyAgreement**, **certSign**, **crlSign**,
+    **encipherOnly**, **decipherOnly**`,
+			},
+			cli.StringFlag{
+				Name: "ext-key-usage",
+				Usage: `Comma-separated list of extended key usages to set on the certificate.
+
+: <ext-key-usage> is a case-sensitive string and must be a comma-separated
+list of one or more of:
+    **serverAuth**, **clientAuth**, **codeSigning**, **emailProtection**,
+    **timeStamping**, **ocspSigning**`,
+			},
+			cli.BoolFlag{
+				Name:  "is-ca",
+				Usage: `Set the certificate's IsCA (BasicConstraints) to true. Implies **certSign** in **--key-usage**.`,
+			},
+			cli.IntFlag{
+				Name:  "path-len",
+				Usage: `The certificate's BasicConstraints path length. Requires **--is-ca**.`,
+			},
+			cli.StringFlag{
+				Name:  "kty",
+				Value: "EC",
+				Usage: `The key type to generate for the certificate or CSR.
+
+: <kty> is a case-sensitive string and must be one of:
+    **EC**
+    :  An elliptic curve key, sized with **--curve**.
+
+    **RSA**
+    :  An RSA key, sized with **--size**.
+
+    **OKP**
+    :  An Ed25519 key.`,
+			},
+			cli.StringFlag{
+				Name:  "curve",
+				Value: "P-256",
+				Usage: `The elliptic curve to use with **--kty**=EC. Must be one of: **P-256**, **P-384**, **P-521**.`,
+			},
+			cli.IntFlag{
+				Name:  "size",
+				Value: 2048,
+				Usage: `The key size, in bits, to use with **--kty**=RSA.`,
+			},
 		},
 	}
 }
@@ -125,26 +253,51 @@ func createAction(ctx *cli.Context) error {
 	prof := ctx.String("profile")
 	caPath := ctx.String("ca")
 	caKeyPath := ctx.String("ca-key")
+	caKMSURI := ctx.String("ca-kms")
+	kmsURI := ctx.String("kms")
 	if ctx.Bool("csr") {
 		typ = "x509-csr"
 	}
+	// "leaf" is the profile Value's default for the x509 flow; reset it to
+	// the sensible ssh default so '--type=ssh' doesn't require '--profile'.
+	if typ == "ssh" && !ctx.IsSet("profile") {
+		prof = "user"
+	}
 
 	var (
-		err    error
-		priv   interface{}
-		pubPEM *pem.Block
+		err       error
+		priv      interface{}
+		pubPEM    *pem.Block
+		kmsKeyRef string
 	)
 	switch typ {
 	case "x509-csr":
-		priv, err = keys.GenerateDefaultKey()
+		if kmsURI != "" {
+			var signer crypto.Signer
+			signer, kmsKeyRef, err = kms.GenerateKey(kmsURI)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			priv = signer
+		} else {
+			priv, err = generateSubjectKey(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		dnsNames, ips, emails, uris, err := parseSANs(ctx)
 		if err != nil {
 			return errors.WithStack(err)
 		}
-
 		_csr := &x509.CertificateRequest{
 			Subject: pkix.Name{
 				CommonName: subject,
 			},
+			DNSNames:       dnsNames,
+			IPAddresses:    ips,
+			EmailAddresses: emails,
+			URIs:           uris,
 		}
 		csrBytes, err := x509.CreateCertificateRequest(rand.Reader, _csr, priv)
 		if err != nil {
@@ -160,33 +313,50 @@ func createAction(ctx *cli.Context) error {
 		var (
 			err     error
 			profile stepx509.Profile
+			tmplOps = []stepx509.Option{
+				stepx509.WithPublicKeyAlgorithm(ctx.String("kty"), ctx.String("curve"), ctx.Int("size")),
+			}
 		)
+		if kmsURI != "" {
+			// Generate the subject key inside the KMS instead of in-process.
+			tmplOps = append(tmplOps, stepx509.WithKMSKey(kmsURI))
+		}
 		switch prof {
 		case "leaf":
-			issIdentity, err := loadIssuerIdentity(prof, caPath, caKeyPath)
+			tmpl, err := buildCertTemplate(ctx, defaultLeafValidity)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			issIdentity, err := loadIssuerIdentity(prof, caPath, caKeyPath, caKMSURI)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 			profile, err = stepx509.NewLeafProfile(subject, issIdentity.Crt,
-				issIdentity.Key)
+				issIdentity.Key, append(tmplOps, stepx509.WithTemplate(tmpl))...)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 		case "intermediate-ca":
-			issIdentity, err := loadIssuerIdentity(prof, caPath, caKeyPath)
+			tmpl, err := buildCertTemplate(ctx, defaultLeafValidity)
 			if err != nil {
 				return errors.WithStack(err)
 			}
+			issIdentity, err := loadIssuerIdentity(prof, caPath, caKeyPath, caKMSURI)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 			profile, err = stepx509.NewIntermediateProfile(subject,
-				issIdentity.Crt, issIdentity.Key)
+				issIdentity.Crt, issIdentity.Key, append(tmplOps, stepx509.WithTemplate(tmpl))...)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 		case "root-ca":
-			profile, err = stepx509.NewRootProfile(subject)
+			tmpl, err := buildCertTemplate(ctx, defaultLeafValidity)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			profile, err = stepx509.NewRootProfile(subject,
+				append(tmplOps, stepx509.WithTemplate(tmpl))...)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -203,15 +373,36 @@ func createAction(ctx *cli.Context) error {
 			Headers: map[string]string{},
 		}
 		priv = profile.SubjectPrivateKey()
+		if kmsURI != "" {
+			kmsKeyRef = profile.SubjectKeyReference()
+		}
 	case "ssh":
-		return errors.Errorf("implementation incomplete! Come back later ...")
+		sshCrtBytes, sshPriv, err := createSSHCertificate(ctx, subject, prof, caKeyPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := ioutil.WriteFile(crtFile, sshCrtBytes, os.FileMode(0600)); err != nil {
+			return errs.FileError(err, crtFile)
+		}
+		priv = sshPriv
 	default:
 		return errs.InvalidFlagValue(ctx, "type", typ, "x509, ssh")
 	}
 
-	if err := ioutil.WriteFile(crtFile, pem.EncodeToMemory(pubPEM),
-		os.FileMode(0600)); err != nil {
-		return errs.FileError(err, crtFile)
+	if typ != "ssh" {
+		if err := ioutil.WriteFile(crtFile, pem.EncodeToMemory(pubPEM),
+			os.FileMode(0600)); err != nil {
+			return errs.FileError(err, crtFile)
+		}
+	}
+
+	// A KMS-backed subject key never leaves the KMS; KEY_FILE gets a
+	// reference URI instead of key material, so there's nothing to encrypt.
+	if kmsKeyRef != "" {
+		if err := ioutil.WriteFile(keyFile, []byte(kmsKeyRef+"\n"), os.FileMode(0600)); err != nil {
+			return errs.FileError(err, keyFile)
+		}
+		return nil
 	}
 
 	var pass string
@@ -228,15 +419,26 @@ func createAction(ctx *cli.Context) error {
 	return nil
 }
 
-func loadIssuerIdentity(profile, caPath, caKeyPath string) (*stepx509.Identity, error) {
+// loadIssuerIdentity loads the issuer certificate and private key used to
+// sign a leaf or intermediate certificate. If caKMSURI is given, the key is
+// located in the KMS at that URI instead of being read from caKeyPath,
+// allowing a CA to be kept on an HSM; caKeyPath and caKMSURI are mutually
+// exclusive.
+func loadIssuerIdentity(profile, caPath, caKeyPath, caKMSURI string) (*stepx509.Identity, error) {
 	if caPath == "" {
 		return nil, errors.Errorf("Missing value for flag '--ca'.\n\nFlags "+
 			"'--ca' and '--ca-key' are required when creating a %s x509 Certificate.",
 			strings.Title(profile))
 	}
+	if caKeyPath != "" && caKMSURI != "" {
+		return nil, errors.Errorf("flags '--ca-key' and '--ca-kms' are mutually exclusive")
+	}
+	if caKMSURI != "" {
+		return stepx509.LoadIdentityFromKMS(caPath, caKMSURI)
+	}
 	if caKeyPath == "" {
 		return nil, errors.Errorf("Missing value for flag '--ca-key'.\n\nFlags "+
-			"'--ca' and '--ca-key' are required when creating a %s x509 Certificate.",
+			"'--ca' and '--ca-key' (or '--ca-kms') are required when creating a %s x509 Certificate.",
 			strings.Title(profile))
 	}
 	return stepx509.LoadIdentityFromDisk(caPath, caKeyPath,
@@ -251,3 +453,42 @@ func loadIssuerIdentity(profile, caPath, caKeyPath string) (*stepx509.Identity,
 		})
 
 }
+
+// parseValidity turns the --not-before/--not-after flag values into a
+// validity window. Each value may be an absolute RFC3339 timestamp or a
+// duration (e.g. "8760h"), relative to now for notBefore and relative to
+// not-before for notAfter. notBefore defaults to now and notAfter defaults
+// to notBefore plus defaultDuration.
+func parseValidity(notBefore, notAfter string, defaultDuration time.Duration) (time.Time, time.Time, error) {
+	nb := time.Now()
+	if notBefore != "" {
+		t, err := parseTimeOrDuration(notBefore, nb)
+		if err != nil {
+			return nb, nb, errors.Wrapf(err, "invalid value for flag '--not-before'")
+		}
+		nb = t
+	}
+
+	na := nb.Add(defaultDuration)
+	if notAfter != "" {
+		t, err := parseTimeOrDuration(notAfter, nb)
+		if err != nil {
+			return nb, na, errors.Wrapf(err, "invalid value for flag '--not-after'")
+		}
+		na = t
+	}
+
+	if !na.After(nb) {
+		return nb, na, errors.Errorf("'--not-after' (%s) must be after '--not-before' (%s)", na, nb)
+	}
+	return nb, na, nil
+}
+
+// parseTimeOrDuration parses value as an RFC3339 timestamp, falling back to
+// a duration relative to base (e.g. "8760h").
+func parseTimeOrDuration(value string, base time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return base.Add(d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
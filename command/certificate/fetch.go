@@ -0,0 +1,161 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/errs"
+	"github.com/urfave/cli"
+)
+
+func fetchCommand() cli.Command {
+	return cli.Command{
+		Name:      "fetch",
+		Action:    cli.ActionFunc(fetchAction),
+		Usage:     `fetch a certificate chain from a TLS server.`,
+		UsageText: `step certificate fetch <host:port> [**--servername**=<name>] [**--starttls**=<protocol>] [**--out**=<file>]`,
+		Description: `**step certificate fetch** connects to <host:port>, performs (or
+upgrades to, for **--starttls**) a TLS handshake, and writes the peer
+certificate chain presented by the server as a PEM bundle to **--out**, or to
+standard output.
+
+  POSITIONAL ARGUMENTS
+    <host:port>
+      The address to connect to.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "servername",
+				Usage: `The TLS server name to send in the ClientHello. Defaults to the host in <host:port>.`,
+			},
+			cli.StringFlag{
+				Name: "starttls",
+				Usage: `Upgrade a plaintext connection to TLS using the STARTTLS handshake for
+the given protocol.
+
+: <protocol> is a case-sensitive string and must be one of:
+    **smtp**, **imap**, **ldap**`,
+			},
+			cli.StringFlag{
+				Name:  "proto",
+				Value: "tcp",
+				Usage: `The network to dial, as used by 'net.Dial'.`,
+			},
+			cli.StringFlag{
+				Name:  "client-cert",
+				Usage: `A client certificate (PEM file) to present during the handshake.`,
+			},
+			cli.StringFlag{
+				Name:  "client-key",
+				Usage: `The private key (PEM file) for **--client-cert**.`,
+			},
+			cli.StringFlag{
+				Name:  "out",
+				Usage: `File to write the certificate chain to. Defaults to standard output.`,
+			},
+		},
+	}
+}
+
+func fetchAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+	addr := ctx.Args().Get(0)
+
+	serverName := ctx.String("servername")
+	if serverName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			serverName = host
+		} else {
+			serverName = addr
+		}
+	}
+
+	clientCertPath := ctx.String("client-cert")
+	clientKeyPath := ctx.String("client-key")
+	if (clientCertPath == "") != (clientKeyPath == "") {
+		return errs.RequiredWithFlag(ctx, "client-cert", "client-key")
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	}
+	if clientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	conn, err := net.Dial(ctx.String("proto"), addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to '%s'", addr)
+	}
+
+	defer conn.Close()
+
+	var state tls.ConnectionState
+	if proto := ctx.String("starttls"); proto != "" {
+		state, err = startTLSUpgrade(proto, conn, serverName, tlsConfig)
+		if err != nil {
+			return err
+		}
+	} else {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return errors.Wrapf(err, "TLS handshake with '%s' failed", addr)
+		}
+		state = tlsConn.ConnectionState()
+	}
+
+	var buf bytes.Buffer
+	for _, crt := range state.PeerCertificates {
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	out := ctx.String("out")
+	if out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := ioutil.WriteFile(out, buf.Bytes(), os.FileMode(0600)); err != nil {
+		return errs.FileError(err, out)
+	}
+	return nil
+}
+
+// startTLSUpgrade negotiates a STARTTLS upgrade on conn for the given
+// plaintext protocol and returns the resulting TLS connection state.
+func startTLSUpgrade(proto string, conn net.Conn, serverName string, tlsConfig *tls.Config) (tls.ConnectionState, error) {
+	switch proto {
+	case "smtp":
+		c, err := smtp.NewClient(conn, serverName)
+		if err != nil {
+			return tls.ConnectionState{}, errors.Wrap(err, "SMTP handshake failed")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return tls.ConnectionState{}, errors.Wrap(err, "SMTP STARTTLS failed")
+		}
+		state, ok := c.TLSConnectionState()
+		if !ok {
+			return tls.ConnectionState{}, errors.Errorf("SMTP server did not upgrade the connection to TLS")
+		}
+		return state, nil
+	case "imap":
+		return imapStartTLS(conn, tlsConfig)
+	case "ldap":
+		return ldapStartTLS(conn, tlsConfig)
+	default:
+		return tls.ConnectionState{}, errors.Errorf("'%s' is not a supported --starttls protocol; must be smtp, imap, or ldap", proto)
+	}
+}
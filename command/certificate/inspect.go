@@ -0,0 +1,396 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/errs"
+	"github.com/urfave/cli"
+)
+
+func inspectCommand() cli.Command {
+	return cli.Command{
+		Name:      "inspect",
+		Action:    cli.ActionFunc(inspectAction),
+		Usage:     `print the details of a certificate, CSR, or TLS server chain.`,
+		UsageText: `step certificate inspect <crt_file|csr_file|-|host:port> [**--format**=<format>] [**--short**]`,
+		Description: `**step certificate inspect** parses a PEM or DER x.509 certificate, a
+certificate signing request, or (when given a <host:port>) the certificate
+chain presented by a TLS server, and prints its details: issuer, subject,
+Subject Alternative Names, key usage, extended key usage, Subject/Authority
+Key Identifiers, signature algorithm, validity (including time until
+expiry), and SHA-1/SHA-256 fingerprints.
+
+When the input is a chain of more than one certificate, each is printed with
+its index, leaf first, and the chain is checked for self-consistency: each
+certificate's 'AuthorityKeyId' should match the next certificate's
+'SubjectKeyId'.
+
+  POSITIONAL ARGUMENTS
+    <crt_file|csr_file|-|host:port>
+      The path to a PEM or DER certificate or CSR, '-' to read one from
+      standard input, or a <host:port> to fetch a certificate chain from
+      over TLS.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: `The output format.
+
+: <format> is a case-sensitive string and must be one of:
+    **text**
+    :  Human-readable output (default).
+
+    **json**
+    :  A JSON array of certificate details, or a single JSON object for a
+       certificate signing request.
+
+    **pem**
+    :  Re-encode the input as PEM, unchanged.
+
+    **der**
+    :  Re-encode the input as raw DER, unchanged. For a chain, only the
+       first (leaf) certificate is written.`,
+			},
+			cli.BoolFlag{
+				Name: "short",
+				Usage: `Print a single-line summary per certificate, suitable for scripts:
+'<subject>\t<not after>\t<sha256 fingerprint>'.`,
+			},
+		},
+	}
+}
+
+func inspectAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+	arg := ctx.Args().Get(0)
+	format := ctx.String("format")
+
+	certs, csr, err := loadInspectable(arg)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "pem", "der":
+		return printInspectableRaw(certs, csr, format)
+	case "json":
+		return printInspectJSON(certs, csr)
+	case "text", "":
+		return printInspectText(ctx, certs, csr)
+	default:
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json, pem, der")
+	}
+}
+
+// loadInspectable resolves arg to either a certificate chain (leaf first) or
+// a certificate signing request, reading a file, standard input, or a
+// <host:port> TLS peer chain as appropriate.
+func loadInspectable(arg string) ([]*x509.Certificate, *x509.CertificateRequest, error) {
+	if arg == "-" {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read standard input")
+		}
+		return parseInspectable(b, "-")
+	}
+
+	if _, err := os.Stat(arg); err != nil {
+		if host, _, splitErr := net.SplitHostPort(arg); splitErr == nil && host != "" {
+			certs, err := fetchPeerCertificates(arg)
+			return certs, nil, err
+		}
+	}
+
+	b, err := ioutil.ReadFile(arg)
+	if err != nil {
+		return nil, nil, errs.FileError(err, arg)
+	}
+	return parseInspectable(b, arg)
+}
+
+// parseInspectable parses b, which may be a PEM certificate chain, a PEM
+// CSR, a DER certificate, or a DER CSR.
+func parseInspectable(b []byte, name string) ([]*x509.Certificate, *x509.CertificateRequest, error) {
+	if block, _ := pem.Decode(b); block != nil {
+		switch block.Type {
+		case "CERTIFICATE":
+			certs, _, err := parseCertificatesPEM(b)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to parse certificate in '%s'", name)
+			}
+			return certs, nil, nil
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to parse certificate request in '%s'", name)
+			}
+			return nil, csr, nil
+		default:
+			return nil, nil, errors.Errorf("'%s' is not a certificate or certificate request", name)
+		}
+	}
+
+	if crt, err := x509.ParseCertificate(b); err == nil {
+		return []*x509.Certificate{crt}, nil, nil
+	}
+	if csr, err := x509.ParseCertificateRequest(b); err == nil {
+		return nil, csr, nil
+	}
+	return nil, nil, errors.Errorf("'%s' is not a PEM or DER certificate or certificate request", name)
+}
+
+// fetchPeerCertificates connects to addr and returns the certificate chain
+// presented during the TLS handshake.
+func fetchPeerCertificates(addr string) ([]*x509.Certificate, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "'%s' is not a valid host:port", addr)
+	}
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to '%s'", addr)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates, nil
+}
+
+func encodeCertificatesPEM(certs []*x509.Certificate) []byte {
+	var buf []byte
+	for _, crt := range certs {
+		buf = append(buf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw})...)
+	}
+	return buf
+}
+
+func printInspectableRaw(certs []*x509.Certificate, csr *x509.CertificateRequest, format string) error {
+	var der []byte
+	switch {
+	case csr != nil:
+		der = csr.Raw
+	case len(certs) > 0:
+		if format == "pem" {
+			_, err := os.Stdout.Write(encodeCertificatesPEM(certs))
+			return err
+		}
+		der = certs[0].Raw
+	default:
+		return errors.Errorf("nothing to print")
+	}
+
+	if format == "pem" {
+		typ := "CERTIFICATE REQUEST"
+		if csr == nil {
+			typ = "CERTIFICATE"
+		}
+		_, err := os.Stdout.Write(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+		return err
+	}
+	_, err := os.Stdout.Write(der)
+	return err
+}
+
+// keyUsageNames returns the human-readable names set in ku, in a stable order.
+func keyUsageNames(ku x509.KeyUsage) []string {
+	var names []string
+	for _, name := range []string{
+		"digitalSignature", "contentCommitment", "keyEncipherment",
+		"dataEncipherment", "keyAgreement", "certSign", "crlSign",
+		"encipherOnly", "decipherOnly",
+	} {
+		if ku&keyUsages[name] != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func extKeyUsageNames(us []x509.ExtKeyUsage) []string {
+	rev := make(map[x509.ExtKeyUsage]string, len(extKeyUsages))
+	for name, u := range extKeyUsages {
+		rev[u] = name
+	}
+	var names []string
+	for _, u := range us {
+		if name, ok := rev[u]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func fingerprints(raw []byte) (sha1Hex, sha256Hex string) {
+	s1 := sha1.Sum(raw)
+	s256 := sha256.Sum256(raw)
+	return hex.EncodeToString(s1[:]), hex.EncodeToString(s256[:])
+}
+
+type certInspection struct {
+	Subject           string   `json:"subject"`
+	Issuer            string   `json:"issuer"`
+	DNSNames          []string `json:"dnsNames,omitempty"`
+	IPAddresses       []string `json:"ipAddresses,omitempty"`
+	EmailAddresses    []string `json:"emailAddresses,omitempty"`
+	URIs              []string `json:"uris,omitempty"`
+	KeyUsage          []string `json:"keyUsage,omitempty"`
+	ExtKeyUsage       []string `json:"extKeyUsage,omitempty"`
+	SubjectKeyID      string   `json:"subjectKeyId"`
+	AuthorityKeyID    string   `json:"authorityKeyId"`
+	SignatureAlgo     string   `json:"signatureAlgorithm"`
+	PublicKeyAlgo     string   `json:"publicKeyAlgorithm"`
+	NotBefore         string   `json:"notBefore"`
+	NotAfter          string   `json:"notAfter"`
+	SHA1Fingerprint   string   `json:"sha1Fingerprint"`
+	SHA256Fingerprint string   `json:"sha256Fingerprint"`
+}
+
+func inspectCertificate(crt *x509.Certificate) certInspection {
+	sha1Hex, sha256Hex := fingerprints(crt.Raw)
+
+	var ips []string
+	for _, ip := range crt.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	var uris []string
+	for _, u := range crt.URIs {
+		uris = append(uris, u.String())
+	}
+
+	return certInspection{
+		Subject:           crt.Subject.String(),
+		Issuer:            crt.Issuer.String(),
+		DNSNames:          crt.DNSNames,
+		IPAddresses:       ips,
+		EmailAddresses:    crt.EmailAddresses,
+		URIs:              uris,
+		KeyUsage:          keyUsageNames(crt.KeyUsage),
+		ExtKeyUsage:       extKeyUsageNames(crt.ExtKeyUsage),
+		SubjectKeyID:      hex.EncodeToString(crt.SubjectKeyId),
+		AuthorityKeyID:    hex.EncodeToString(crt.AuthorityKeyId),
+		SignatureAlgo:     crt.SignatureAlgorithm.String(),
+		PublicKeyAlgo:     crt.PublicKeyAlgorithm.String(),
+		NotBefore:         crt.NotBefore.Format(time.RFC3339),
+		NotAfter:          crt.NotAfter.Format(time.RFC3339),
+		SHA1Fingerprint:   sha1Hex,
+		SHA256Fingerprint: sha256Hex,
+	}
+}
+
+func printInspectJSON(certs []*x509.Certificate, csr *x509.CertificateRequest) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if csr != nil {
+		return enc.Encode(struct {
+			Subject     string   `json:"subject"`
+			DNSNames    []string `json:"dnsNames,omitempty"`
+			IPAddresses []string `json:"ipAddresses,omitempty"`
+		}{
+			Subject:  csr.Subject.String(),
+			DNSNames: csr.DNSNames,
+		})
+	}
+
+	var out []certInspection
+	for _, crt := range certs {
+		out = append(out, inspectCertificate(crt))
+	}
+	return enc.Encode(out)
+}
+
+func printInspectText(ctx *cli.Context, certs []*x509.Certificate, csr *x509.CertificateRequest) error {
+	if csr != nil {
+		if ctx.Bool("short") {
+			fmt.Printf("%s\tCSR\n", csr.Subject.String())
+			return nil
+		}
+		fmt.Printf("Subject: %s\n", csr.Subject.String())
+		if len(csr.DNSNames) > 0 {
+			fmt.Printf("DNS Names: %s\n", strings.Join(csr.DNSNames, ", "))
+		}
+		fmt.Printf("Signature Algorithm: %s\n", csr.SignatureAlgorithm)
+		fmt.Printf("Public Key Algorithm: %s\n", csr.PublicKeyAlgorithm)
+		sha1Hex, sha256Hex := fingerprints(csr.Raw)
+		fmt.Printf("SHA-1 Fingerprint: %s\n", sha1Hex)
+		fmt.Printf("SHA-256 Fingerprint: %s\n", sha256Hex)
+		return nil
+	}
+
+	for i, crt := range certs {
+		info := inspectCertificate(crt)
+		if ctx.Bool("short") {
+			fmt.Printf("%s\t%s\t%s\n", info.Subject, info.NotAfter, info.SHA256Fingerprint)
+			continue
+		}
+
+		if len(certs) > 1 {
+			fmt.Printf("Certificate %d:\n", i)
+		}
+		fmt.Printf("Subject: %s\n", info.Subject)
+		fmt.Printf("Issuer: %s\n", info.Issuer)
+		if len(info.DNSNames) > 0 {
+			fmt.Printf("DNS Names: %s\n", strings.Join(info.DNSNames, ", "))
+		}
+		if len(info.IPAddresses) > 0 {
+			fmt.Printf("IP Addresses: %s\n", strings.Join(info.IPAddresses, ", "))
+		}
+		if len(info.EmailAddresses) > 0 {
+			fmt.Printf("Email Addresses: %s\n", strings.Join(info.EmailAddresses, ", "))
+		}
+		if len(info.URIs) > 0 {
+			fmt.Printf("URIs: %s\n", strings.Join(info.URIs, ", "))
+		}
+		if len(info.KeyUsage) > 0 {
+			fmt.Printf("Key Usage: %s\n", strings.Join(info.KeyUsage, ", "))
+		}
+		if len(info.ExtKeyUsage) > 0 {
+			fmt.Printf("Extended Key Usage: %s\n", strings.Join(info.ExtKeyUsage, ", "))
+		}
+		fmt.Printf("Subject Key ID: %s\n", info.SubjectKeyID)
+		fmt.Printf("Authority Key ID: %s\n", info.AuthorityKeyID)
+		fmt.Printf("Signature Algorithm: %s\n", info.SignatureAlgo)
+		fmt.Printf("Public Key Algorithm: %s\n", info.PublicKeyAlgo)
+		fmt.Printf("Not Before: %s\n", info.NotBefore)
+		fmt.Printf("Not After: %s (%s)\n", info.NotAfter, timeUntil(crt.NotAfter))
+		fmt.Printf("SHA-1 Fingerprint: %s\n", info.SHA1Fingerprint)
+		fmt.Printf("SHA-256 Fingerprint: %s\n", info.SHA256Fingerprint)
+
+		if i < len(certs)-1 {
+			if len(crt.AuthorityKeyId) > 0 && bytes.Equal(crt.AuthorityKeyId, certs[i+1].SubjectKeyId) {
+				fmt.Printf("Chain: linked to certificate %d\n", i+1)
+			} else {
+				fmt.Printf("Chain: NOT linked to certificate %d (AuthorityKeyId does not match its SubjectKeyId)\n", i+1)
+			}
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// timeUntil describes t relative to now, rounded to the hour, for use in
+// "time until expiry" messages (e.g. "expires in 1080h0m0s" or
+// "expired 72h0m0s ago").
+func timeUntil(t time.Time) string {
+	d := time.Until(t)
+	if d < 0 {
+		return fmt.Sprintf("expired %s ago", (-d).Round(time.Hour))
+	}
+	return fmt.Sprintf("expires in %s", d.Round(time.Hour))
+}
@@ -0,0 +1,238 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+// captureStdout redirects os.Stdout for the duration of fn and returns
+// whatever it wrote.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	fn()
+
+	w.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("failed to read captured stdout: %v", err)
+	}
+	return buf.String()
+}
+
+func TestParseInspectable_PEMCertificate(t *testing.T) {
+	_, _, leaf := testIssuerAndLeaf(t)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+
+	certs, csr, err := parseInspectable(pemBytes, "leaf.pem")
+	if err != nil {
+		t.Fatalf("parseInspectable: unexpected error: %v", err)
+	}
+	if csr != nil {
+		t.Fatal("parseInspectable: expected csr to be nil for a certificate")
+	}
+	if len(certs) != 1 || !bytes.Equal(certs[0].Raw, leaf.Raw) {
+		t.Errorf("parseInspectable: got %d certs, want 1 matching the input", len(certs))
+	}
+}
+
+func TestParseInspectable_DERCertificate(t *testing.T) {
+	_, _, leaf := testIssuerAndLeaf(t)
+
+	certs, csr, err := parseInspectable(leaf.Raw, "leaf.der")
+	if err != nil {
+		t.Fatalf("parseInspectable: unexpected error: %v", err)
+	}
+	if csr != nil {
+		t.Fatal("parseInspectable: expected csr to be nil for a certificate")
+	}
+	if len(certs) != 1 || !bytes.Equal(certs[0].Raw, leaf.Raw) {
+		t.Errorf("parseInspectable: got %d certs, want 1 matching the input", len(certs))
+	}
+}
+
+func testCSR(t *testing.T) *x509.CertificateRequest {
+	t.Helper()
+	_, issuerKey, _ := testIssuerAndLeaf(t)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, issuerKey)
+	if err != nil {
+		t.Fatalf("failed to create CSR: %v", err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %v", err)
+	}
+	return csr
+}
+
+func TestParseInspectable_PEMCertificateRequest(t *testing.T) {
+	csr := testCSR(t)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})
+
+	certs, gotCSR, err := parseInspectable(pemBytes, "req.pem")
+	if err != nil {
+		t.Fatalf("parseInspectable: unexpected error: %v", err)
+	}
+	if certs != nil {
+		t.Fatal("parseInspectable: expected certs to be nil for a CSR")
+	}
+	if gotCSR == nil || !bytes.Equal(gotCSR.Raw, csr.Raw) {
+		t.Error("parseInspectable: returned CSR does not match the input")
+	}
+}
+
+func TestParseInspectable_DERCertificateRequest(t *testing.T) {
+	csr := testCSR(t)
+
+	certs, gotCSR, err := parseInspectable(csr.Raw, "req.der")
+	if err != nil {
+		t.Fatalf("parseInspectable: unexpected error: %v", err)
+	}
+	if certs != nil {
+		t.Fatal("parseInspectable: expected certs to be nil for a CSR")
+	}
+	if gotCSR == nil || !bytes.Equal(gotCSR.Raw, csr.Raw) {
+		t.Error("parseInspectable: returned CSR does not match the input")
+	}
+}
+
+func TestParseInspectable_Invalid(t *testing.T) {
+	if _, _, err := parseInspectable([]byte("not a certificate"), "garbage"); err == nil {
+		t.Error("parseInspectable with garbage input: expected an error, got none")
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("bogus")})
+	if _, _, err := parseInspectable(pemBytes, "key.pem"); err == nil {
+		t.Error("parseInspectable with a non-certificate PEM block: expected an error, got none")
+	}
+}
+
+func TestKeyUsageNames(t *testing.T) {
+	ku := x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
+	names := keyUsageNames(ku)
+	if len(names) != 2 || names[0] != "digitalSignature" || names[1] != "certSign" {
+		t.Errorf("keyUsageNames(%v) = %v, want [digitalSignature certSign]", ku, names)
+	}
+	if got := keyUsageNames(0); got != nil {
+		t.Errorf("keyUsageNames(0) = %v, want nil", got)
+	}
+}
+
+func TestExtKeyUsageNames(t *testing.T) {
+	us := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	names := extKeyUsageNames(us)
+	if len(names) != 2 || names[0] != "serverAuth" || names[1] != "clientAuth" {
+		t.Errorf("extKeyUsageNames(%v) = %v, want [serverAuth clientAuth]", us, names)
+	}
+}
+
+func newInspectTestContext(t *testing.T, args []string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := []cli.Flag{
+		cli.StringFlag{Name: "format", Value: "text"},
+		cli.BoolFlag{Name: "short"},
+	}
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags %v: %v", args, err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestPrintInspectText_ChainLinking(t *testing.T) {
+	issuer, _, leaf := testIssuerAndLeaf(t)
+	ctx := newInspectTestContext(t, nil)
+
+	linked := captureStdout(t, func() {
+		if err := printInspectText(ctx, []*x509.Certificate{leaf, issuer}, nil); err != nil {
+			t.Fatalf("printInspectText: unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(linked, "linked to certificate 1") || strings.Contains(linked, "NOT linked") {
+		t.Errorf("printInspectText with a valid chain: expected a linked chain message, got:\n%s", linked)
+	}
+
+	other, _, _ := testIssuerAndLeaf(t)
+	notLinked := captureStdout(t, func() {
+		if err := printInspectText(ctx, []*x509.Certificate{leaf, other}, nil); err != nil {
+			t.Fatalf("printInspectText: unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(notLinked, "NOT linked to certificate 1") {
+		t.Errorf("printInspectText with a broken chain: expected a NOT linked message, got:\n%s", notLinked)
+	}
+}
+
+func TestPrintInspectText_Short(t *testing.T) {
+	_, _, leaf := testIssuerAndLeaf(t)
+	ctx := newInspectTestContext(t, []string{"--short"})
+
+	out := captureStdout(t, func() {
+		if err := printInspectText(ctx, []*x509.Certificate{leaf}, nil); err != nil {
+			t.Fatalf("printInspectText: unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(out, leaf.Subject.String()) {
+		t.Errorf("printInspectText --short: expected the subject in the summary line, got:\n%s", out)
+	}
+	if strings.Contains(out, "Subject Key ID") {
+		t.Errorf("printInspectText --short: expected a single summary line, got the full text output:\n%s", out)
+	}
+}
+
+func TestPrintInspectableRaw_PEMAndDER(t *testing.T) {
+	_, _, leaf := testIssuerAndLeaf(t)
+
+	pemOut := captureStdout(t, func() {
+		if err := printInspectableRaw([]*x509.Certificate{leaf}, nil, "pem"); err != nil {
+			t.Fatalf("printInspectableRaw(pem): unexpected error: %v", err)
+		}
+	})
+	block, _ := pem.Decode([]byte(pemOut))
+	if block == nil || block.Type != "CERTIFICATE" || !bytes.Equal(block.Bytes, leaf.Raw) {
+		t.Errorf("printInspectableRaw(pem): output does not round-trip to the input certificate")
+	}
+
+	derOut := captureStdout(t, func() {
+		if err := printInspectableRaw([]*x509.Certificate{leaf}, nil, "der"); err != nil {
+			t.Fatalf("printInspectableRaw(der): unexpected error: %v", err)
+		}
+	})
+	if !bytes.Equal([]byte(derOut), leaf.Raw) {
+		t.Error("printInspectableRaw(der): output does not match the input certificate's raw DER")
+	}
+}
+
+func TestPrintInspectJSON(t *testing.T) {
+	_, _, leaf := testIssuerAndLeaf(t)
+
+	out := captureStdout(t, func() {
+		if err := printInspectJSON([]*x509.Certificate{leaf}, nil); err != nil {
+			t.Fatalf("printInspectJSON: unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(out, `"subject"`) || !strings.Contains(out, leaf.Subject.CommonName) {
+		t.Errorf("printInspectJSON: expected JSON containing the subject, got:\n%s", out)
+	}
+}
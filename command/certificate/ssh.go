@@ -0,0 +1,170 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/crypto/keys"
+	"github.com/smallstep/cli/utils/reader"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultSSHValidity is used when --not-after is not given.
+const defaultSSHValidity = 24 * time.Hour
+
+// createSSHCertificate builds an SSH certificate for <subject>, signing it
+// with the CA key at caKeyPath, or self-signing it with the freshly
+// generated subject key when caKeyPath is empty. It returns the certificate
+// in 'ssh-*-cert-v01@openssh.com' authorized-keys format and the subject's
+// private key, ready to be written to disk by the caller.
+func createSSHCertificate(ctx *cli.Context, subject, profile, caKeyPath string) ([]byte, interface{}, error) {
+	certType, err := sshCertType(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	priv, err := keys.GenerateDefaultKey()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.Errorf("generated key of type %T does not implement crypto.Signer", priv)
+	}
+	sshPub, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to convert generated key to an SSH public key")
+	}
+
+	notBefore, notAfter, err := parseValidity(ctx.String("not-before"), ctx.String("not-after"), defaultSSHValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extensions, err := sshKeyValueMap(ctx.StringSlice("extension"))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid value for flag '--extension'")
+	}
+	criticalOptions, err := sshKeyValueMap(ctx.StringSlice("critical-option"))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid value for flag '--critical-option'")
+	}
+
+	keyID := ctx.String("key-id")
+	if keyID == "" {
+		keyID = subject
+	}
+
+	var principals []string
+	if p := ctx.String("principals"); p != "" {
+		for _, name := range strings.Split(p, ",") {
+			principals = append(principals, strings.TrimSpace(name))
+		}
+	}
+
+	serial, err := sshRandomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cert := &ssh.Certificate{
+		Key:             sshPub,
+		Serial:          serial,
+		CertType:        certType,
+		KeyId:           keyID,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(notBefore.Unix()),
+		ValidBefore:     uint64(notAfter.Unix()),
+		Permissions: ssh.Permissions{
+			CriticalOptions: criticalOptions,
+			Extensions:      extensions,
+		},
+	}
+
+	caSigner, err := loadSSHSigner(caKeyPath, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to sign SSH certificate")
+	}
+
+	return ssh.MarshalAuthorizedKey(cert), priv, nil
+}
+
+// sshCertType maps the --profile value to the ssh.Certificate CertType.
+func sshCertType(profile string) (uint32, error) {
+	switch profile {
+	case "", "user":
+		return ssh.UserCert, nil
+	case "host":
+		return ssh.HostCert, nil
+	default:
+		return 0, errors.Errorf("invalid value '%s' for flag '--profile'; must be 'user' or 'host' when --type=ssh", profile)
+	}
+}
+
+// sshKeyValueMap parses a list of "key=value" flag values into a map, as
+// used for SSH extensions and critical options.
+func sshKeyValueMap(kvs []string) (map[string]string, error) {
+	if len(kvs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("'%s' is not in the form 'key=value'", kv)
+		}
+		out[parts[0]] = parts[1]
+	}
+	return out, nil
+}
+
+// sshRandomSerial returns a random, non-zero certificate serial number.
+func sshRandomSerial() (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	serial := binary.BigEndian.Uint64(b[:])
+	if serial == 0 {
+		serial = 1
+	}
+	return serial, nil
+}
+
+// loadSSHSigner returns the CA signer used to sign the new SSH certificate.
+// When caKeyPath is empty the certificate is self-signed with subjectSigner.
+func loadSSHSigner(caKeyPath string, subjectSigner crypto.Signer) (ssh.Signer, error) {
+	if caKeyPath == "" {
+		return ssh.NewSignerFromSigner(subjectSigner)
+	}
+
+	caKeyBytes, err := ioutil.ReadFile(caKeyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read CA key file '%s'", caKeyPath)
+	}
+
+	signer, err := ssh.ParsePrivateKey(caKeyBytes)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		var pass string
+		if perr := reader.ReadPasswordSubtle(
+			fmt.Sprintf("Password with which to decrypt CA private key file `%s`: ", caKeyPath),
+			&pass, "Password", reader.RetryOnEmpty); perr != nil {
+			return nil, errors.WithStack(perr)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(caKeyBytes, []byte(pass))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse CA key file '%s'", caKeyPath)
+	}
+	return signer, nil
+}
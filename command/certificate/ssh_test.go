@@ -0,0 +1,81 @@
+package certificate
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHCertType(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    uint32
+		wantErr bool
+	}{
+		{"", ssh.UserCert, false},
+		{"user", ssh.UserCert, false},
+		{"host", ssh.HostCert, false},
+		{"leaf", 0, true},
+		{"root-ca", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := sshCertType(tt.profile)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("sshCertType(%q): expected an error, got none", tt.profile)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("sshCertType(%q): unexpected error: %v", tt.profile, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("sshCertType(%q) = %d, want %d", tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestParseValidity(t *testing.T) {
+	nb, na, err := parseValidity("", "", defaultSSHValidity)
+	if err != nil {
+		t.Fatalf("parseValidity: unexpected error: %v", err)
+	}
+	if got := na.Sub(nb); got != defaultSSHValidity {
+		t.Errorf("default validity window = %s, want %s", got, defaultSSHValidity)
+	}
+
+	nb, na, err = parseValidity("2020-01-01T00:00:00Z", "8760h", defaultSSHValidity)
+	if err != nil {
+		t.Fatalf("parseValidity: unexpected error: %v", err)
+	}
+	wantNB := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !nb.Equal(wantNB) {
+		t.Errorf("not-before = %s, want %s", nb, wantNB)
+	}
+	if got := na.Sub(nb); got != 8760*time.Hour {
+		t.Errorf("not-after - not-before = %s, want 8760h", got)
+	}
+
+	if _, _, err := parseValidity("", "-1h", defaultSSHValidity); err == nil {
+		t.Error("parseValidity with --not-after before --not-before: expected an error, got none")
+	}
+}
+
+func TestSSHKeyValueMap(t *testing.T) {
+	m, err := sshKeyValueMap([]string{"permit-pty=", "force-command=/bin/true"})
+	if err != nil {
+		t.Fatalf("sshKeyValueMap: unexpected error: %v", err)
+	}
+	if got, want := m["permit-pty"], ""; got != want {
+		t.Errorf(`m["permit-pty"] = %q, want %q`, got, want)
+	}
+	if got, want := m["force-command"], "/bin/true"; got != want {
+		t.Errorf(`m["force-command"] = %q, want %q`, got, want)
+	}
+
+	if _, err := sshKeyValueMap([]string{"no-equals-sign"}); err == nil {
+		t.Error("sshKeyValueMap with a malformed entry: expected an error, got none")
+	}
+}
@@ -0,0 +1,188 @@
+package certificate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// imapStartTLS issues the IMAP4rev1 STARTTLS command (RFC 3501 §6.2.1) on
+// conn, reads the server's tagged response, and on success upgrades the
+// connection to TLS.
+func imapStartTLS(conn net.Conn, tlsConfig *tls.Config) (tls.ConnectionState, error) {
+	r := bufio.NewReader(conn)
+
+	// The server greeting.
+	if _, err := r.ReadString('\n'); err != nil {
+		return tls.ConnectionState{}, errors.Wrap(err, "failed to read IMAP greeting")
+	}
+
+	const tag = "a1"
+	if _, err := conn.Write([]byte(tag + " STARTTLS\r\n")); err != nil {
+		return tls.ConnectionState{}, errors.Wrap(err, "failed to send IMAP STARTTLS command")
+	}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return tls.ConnectionState{}, errors.Wrap(err, "failed to read IMAP STARTTLS response")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(line, tag+" ") {
+			// An untagged response; keep reading until the tagged one arrives.
+			continue
+		}
+		if !strings.HasPrefix(line, tag+" OK") {
+			return tls.ConnectionState{}, errors.Errorf("IMAP server rejected STARTTLS: %s", line)
+		}
+		break
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return tls.ConnectionState{}, errors.Wrap(err, "TLS handshake after IMAP STARTTLS failed")
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+// ldapStartTLSOID is the LDAPv3 Start TLS extended operation OID (RFC 4511 §4.14).
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// ldapStartTLS issues the LDAPv3 Start TLS extended operation (RFC 4511
+// §4.14) on conn as a minimal, hand-rolled BER-encoded ExtendedRequest, reads
+// the server's ExtendedResponse, and on success upgrades the connection to
+// TLS.
+func ldapStartTLS(conn net.Conn, tlsConfig *tls.Config) (tls.ConnectionState, error) {
+	req := ldapExtendedRequest(1, ldapStartTLSOID)
+	if _, err := conn.Write(req); err != nil {
+		return tls.ConnectionState{}, errors.Wrap(err, "failed to send LDAP StartTLS extended request")
+	}
+
+	r := bufio.NewReader(conn)
+	resultCode, err := ldapReadExtendedResponse(r)
+	if err != nil {
+		return tls.ConnectionState{}, errors.Wrap(err, "failed to read LDAP StartTLS extended response")
+	}
+	if resultCode != 0 {
+		return tls.ConnectionState{}, errors.Errorf("LDAP server rejected StartTLS with result code %d", resultCode)
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return tls.ConnectionState{}, errors.Wrap(err, "TLS handshake after LDAP StartTLS failed")
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+// ldapExtendedRequest builds the BER encoding of an LDAPMessage wrapping an
+// ExtendedRequest [APPLICATION 23] carrying the given request OID as its
+// requestName [0].
+func ldapExtendedRequest(messageID int, oid string) []byte {
+	// requestName [0], context-specific primitive.
+	berOID := berTLV(0x80, []byte(oid))
+	// ExtendedRequest ::= [APPLICATION 23]
+	extendedRequest := berTLV(0x77, berOID)
+	// INTEGER messageID
+	messageIDTLV := berTLV(0x02, berInt(messageID))
+	// LDAPMessage ::= SEQUENCE
+	return berTLV(0x30, append(messageIDTLV, extendedRequest...))
+}
+
+// ldapReadExtendedResponse reads a single LDAPMessage from r and returns the
+// resultCode of the ExtendedResponse [APPLICATION 24] it carries.
+func ldapReadExtendedResponse(r *bufio.Reader) (int, error) {
+	tag, body, err := berReadTLV(r)
+	if err != nil {
+		return 0, err
+	}
+	if tag != 0x30 {
+		return 0, errors.Errorf("expected a SEQUENCE, got BER tag 0x%02x", tag)
+	}
+	inner := bufio.NewReader(bytes.NewReader(body))
+
+	// messageID
+	if _, _, err := berReadTLV(inner); err != nil {
+		return 0, err
+	}
+	tag, body, err = berReadTLV(inner)
+	if err != nil {
+		return 0, err
+	}
+	if tag != 0x78 {
+		return 0, errors.Errorf("expected an ExtendedResponse, got BER tag 0x%02x", tag)
+	}
+
+	protocolOp := bufio.NewReader(bytes.NewReader(body))
+	tag, resultCodeBytes, err := berReadTLV(protocolOp)
+	if err != nil {
+		return 0, err
+	}
+	if tag != 0x0a {
+		return 0, errors.Errorf("expected an ENUMERATED resultCode, got BER tag 0x%02x", tag)
+	}
+	return berDecodeInt(resultCodeBytes), nil
+}
+
+// berTLV encodes a BER tag-length-value with a length in short or
+// long-definite form, sufficient for the small messages StartTLS exchanges.
+func berTLV(tag byte, value []byte) []byte {
+	return append([]byte{tag}, append(berLength(len(value)), value...)...)
+}
+
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for n > 0 {
+		b = append([]byte{byte(n & 0xff)}, b...)
+		n >>= 8
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func berInt(n int) []byte {
+	return []byte{byte(n)}
+}
+
+func berDecodeInt(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+	return n
+}
+
+// berReadTLV reads one BER tag-length-value from r.
+func berReadTLV(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	length := int(first)
+	if first&0x80 != 0 {
+		numBytes := int(first &^ 0x80)
+		length = 0
+		for i := 0; i < numBytes; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	return tag, value, nil
+}
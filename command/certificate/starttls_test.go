@@ -0,0 +1,105 @@
+package certificate
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestBERTLVRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		tag   byte
+		value []byte
+	}{
+		{"empty value", 0x04, nil},
+		{"short value", 0x04, []byte("hello")},
+		{"long value needs multi-byte length", 0x04, bytes.Repeat([]byte{0x41}, 200)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := berTLV(tt.tag, tt.value)
+			tag, value, err := berReadTLV(bufio.NewReader(bytes.NewReader(encoded)))
+			if err != nil {
+				t.Fatalf("berReadTLV: unexpected error: %v", err)
+			}
+			if tag != tt.tag {
+				t.Errorf("tag = 0x%02x, want 0x%02x", tag, tt.tag)
+			}
+			if !bytes.Equal(value, tt.value) {
+				t.Errorf("value = %v, want %v", value, tt.value)
+			}
+		})
+	}
+}
+
+func TestBERLength(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x81, 0x80}},
+		{256, []byte{0x82, 0x01, 0x00}},
+	}
+	for _, tt := range tests {
+		if got := berLength(tt.n); !bytes.Equal(got, tt.want) {
+			t.Errorf("berLength(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBERIntRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 42, 255} {
+		if got := berDecodeInt(berInt(n)); got != n {
+			t.Errorf("berDecodeInt(berInt(%d)) = %d, want %d", n, got, n)
+		}
+	}
+}
+
+// TestLDAPExtendedRequest checks that ldapExtendedRequest produces a
+// well-formed LDAPMessage SEQUENCE wrapping the requestName as a BER
+// [0] primitive inside an ExtendedRequest [APPLICATION 23].
+func TestLDAPExtendedRequest(t *testing.T) {
+	req := ldapExtendedRequest(1, ldapStartTLSOID)
+
+	tag, body, err := berReadTLV(bufio.NewReader(bytes.NewReader(req)))
+	if err != nil {
+		t.Fatalf("berReadTLV on LDAPMessage: unexpected error: %v", err)
+	}
+	if tag != 0x30 {
+		t.Fatalf("LDAPMessage tag = 0x%02x, want 0x30 (SEQUENCE)", tag)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(body))
+	msgIDTag, msgIDBytes, err := berReadTLV(r)
+	if err != nil {
+		t.Fatalf("berReadTLV on messageID: unexpected error: %v", err)
+	}
+	if msgIDTag != 0x02 {
+		t.Errorf("messageID tag = 0x%02x, want 0x02 (INTEGER)", msgIDTag)
+	}
+	if got := berDecodeInt(msgIDBytes); got != 1 {
+		t.Errorf("messageID = %d, want 1", got)
+	}
+
+	extTag, extBody, err := berReadTLV(r)
+	if err != nil {
+		t.Fatalf("berReadTLV on ExtendedRequest: unexpected error: %v", err)
+	}
+	if extTag != 0x77 {
+		t.Fatalf("ExtendedRequest tag = 0x%02x, want 0x77 ([APPLICATION 23])", extTag)
+	}
+
+	oidTag, oidBytes, err := berReadTLV(bufio.NewReader(bytes.NewReader(extBody)))
+	if err != nil {
+		t.Fatalf("berReadTLV on requestName: unexpected error: %v", err)
+	}
+	if oidTag != 0x80 {
+		t.Errorf("requestName tag = 0x%02x, want 0x80 ([0])", oidTag)
+	}
+	if string(oidBytes) != ldapStartTLSOID {
+		t.Errorf("requestName = %q, want %q", oidBytes, ldapStartTLSOID)
+	}
+}
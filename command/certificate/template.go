@@ -0,0 +1,206 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/crypto/keys"
+	"github.com/urfave/cli"
+)
+
+// defaultLeafValidity is used for the leaf, intermediate-ca and root-ca
+// profiles when --not-after is not given.
+const defaultLeafValidity = 8760 * time.Hour
+
+var keyUsages = map[string]x509.KeyUsage{
+	"digitalSignature":  x509.KeyUsageDigitalSignature,
+	"contentCommitment": x509.KeyUsageContentCommitment,
+	"keyEncipherment":   x509.KeyUsageKeyEncipherment,
+	"dataEncipherment":  x509.KeyUsageDataEncipherment,
+	"keyAgreement":      x509.KeyUsageKeyAgreement,
+	"certSign":          x509.KeyUsageCertSign,
+	"crlSign":           x509.KeyUsageCRLSign,
+	"encipherOnly":      x509.KeyUsageEncipherOnly,
+	"decipherOnly":      x509.KeyUsageDecipherOnly,
+}
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"serverAuth":      x509.ExtKeyUsageServerAuth,
+	"clientAuth":      x509.ExtKeyUsageClientAuth,
+	"codeSigning":     x509.ExtKeyUsageCodeSigning,
+	"emailProtection": x509.ExtKeyUsageEmailProtection,
+	"timeStamping":    x509.ExtKeyUsageTimeStamping,
+	"ocspSigning":     x509.ExtKeyUsageOCSPSigning,
+}
+
+// parseKeyUsage parses a comma-separated --key-usage flag value.
+func parseKeyUsage(csv string) (x509.KeyUsage, error) {
+	var ku x509.KeyUsage
+	if csv == "" {
+		return ku, nil
+	}
+	for _, name := range strings.Split(csv, ",") {
+		name = strings.TrimSpace(name)
+		u, ok := keyUsages[name]
+		if !ok {
+			return 0, errors.Errorf("'%s' is not a valid key usage", name)
+		}
+		ku |= u
+	}
+	return ku, nil
+}
+
+// parseExtKeyUsage parses a comma-separated --ext-key-usage flag value.
+func parseExtKeyUsage(csv string) ([]x509.ExtKeyUsage, error) {
+	if csv == "" {
+		return nil, nil
+	}
+	var out []x509.ExtKeyUsage
+	for _, name := range strings.Split(csv, ",") {
+		name = strings.TrimSpace(name)
+		u, ok := extKeyUsages[name]
+		if !ok {
+			return nil, errors.Errorf("'%s' is not a valid extended key usage", name)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// parseSANs reads --dns, --ip, --email and --uri into the fields used by
+// both x509.Certificate and x509.CertificateRequest templates.
+func parseSANs(ctx *cli.Context) (dnsNames []string, ips []net.IP, emails []string, uris []*url.URL, err error) {
+	dnsNames = ctx.StringSlice("dns")
+
+	for _, raw := range ctx.StringSlice("ip") {
+		ip := net.ParseIP(raw)
+		if ip == nil {
+			return nil, nil, nil, nil, errors.Errorf("'%s' is not a valid IP address", raw)
+		}
+		ips = append(ips, ip)
+	}
+
+	if email := ctx.String("email"); email != "" {
+		emails = append(emails, email)
+	}
+
+	if uri := ctx.String("uri"); uri != "" {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, nil, nil, nil, errors.Wrapf(err, "'%s' is not a valid URI", uri)
+		}
+		uris = append(uris, u)
+	}
+
+	return
+}
+
+// buildCertTemplate assembles the *x509.Certificate template fields that are
+// shared across the leaf, intermediate-ca and root-ca profiles from the
+// --dns/--ip/--email/--uri/--not-before/--not-after/--key-usage/
+// --ext-key-usage/--is-ca/--path-len flags.
+func buildCertTemplate(ctx *cli.Context, defaultValidity time.Duration) (*x509.Certificate, error) {
+	dnsNames, ips, emails, uris, err := parseSANs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore, notAfter, err := parseValidity(ctx.String("not-before"), ctx.String("not-after"), defaultValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	keyUsage, err := parseKeyUsage(ctx.String("key-usage"))
+	if err != nil {
+		return nil, err
+	}
+	extKeyUsage, err := parseExtKeyUsage(ctx.String("ext-key-usage"))
+	if err != nil {
+		return nil, err
+	}
+
+	isCA := ctx.Bool("is-ca")
+	pathLenSet := ctx.IsSet("path-len")
+	pathLen := ctx.Int("path-len")
+	if pathLenSet && !isCA {
+		return nil, errors.Errorf("flag '--path-len' requires the '--is-ca' flag")
+	}
+	if isCA {
+		keyUsage |= x509.KeyUsageCertSign
+	}
+
+	return &x509.Certificate{
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+		EmailAddresses:        emails,
+		URIs:                  uris,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		IsCA:                  isCA,
+		BasicConstraintsValid: isCA,
+		MaxPathLen:            pathLen,
+		MaxPathLenZero:        isCA && pathLenSet && pathLen == 0,
+	}, nil
+}
+
+// generateSubjectKey generates the private key used for the certificate or
+// CSR subject, honoring --kty/--curve/--size when given and falling back to
+// the package default otherwise.
+func generateSubjectKey(ctx *cli.Context) (interface{}, error) {
+	kty := ctx.String("kty")
+	if !ctx.IsSet("kty") && !ctx.IsSet("curve") && !ctx.IsSet("size") {
+		return keys.GenerateDefaultKey()
+	}
+
+	ktyUpper := strings.ToUpper(kty)
+	if ctx.IsSet("size") && ktyUpper != "RSA" {
+		return nil, errors.Errorf("flag '--size' is only valid with '--kty=RSA'")
+	}
+	if ctx.IsSet("curve") && ktyUpper != "EC" && ktyUpper != "ECDSA" {
+		return nil, errors.Errorf("flag '--curve' is only valid with '--kty=EC'")
+	}
+
+	switch ktyUpper {
+	case "EC", "ECDSA":
+		curve, err := parseCurve(ctx.String("curve"))
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case "RSA":
+		size := ctx.Int("size")
+		if size == 0 {
+			size = 2048
+		}
+		return rsa.GenerateKey(rand.Reader, size)
+	case "OKP", "ED25519":
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, errors.Errorf("'%s' is not a valid value for flag '--kty'; must be EC, RSA or OKP", kty)
+	}
+}
+
+func parseCurve(name string) (elliptic.Curve, error) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	default:
+		return nil, errors.Errorf("'%s' is not a valid value for flag '--curve'; must be P-256, P-384 or P-521", name)
+	}
+}
@@ -0,0 +1,203 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"flag"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+// newTemplateTestContext builds a *cli.Context carrying the same flags
+// buildCertTemplate and generateSubjectKey read from 'step certificate
+// create', parsed from args.
+func newTemplateTestContext(t *testing.T, args []string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := []cli.Flag{
+		cli.StringSliceFlag{Name: "dns"},
+		cli.StringSliceFlag{Name: "ip"},
+		cli.StringFlag{Name: "email"},
+		cli.StringFlag{Name: "uri"},
+		cli.StringFlag{Name: "not-before"},
+		cli.StringFlag{Name: "not-after"},
+		cli.StringFlag{Name: "key-usage"},
+		cli.StringFlag{Name: "ext-key-usage"},
+		cli.BoolFlag{Name: "is-ca"},
+		cli.IntFlag{Name: "path-len"},
+		cli.StringFlag{Name: "kty", Value: "EC"},
+		cli.StringFlag{Name: "curve", Value: "P-256"},
+		cli.IntFlag{Name: "size", Value: 2048},
+	}
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags %v: %v", args, err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestBuildCertTemplate_IsCAAndPathLen(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		wantErr         bool
+		wantIsCA        bool
+		wantMaxPathLen  int
+		wantPathLenZero bool
+	}{
+		{"neither flag", nil, false, false, 0, false},
+		{"is-ca alone", []string{"--is-ca"}, false, true, 0, false},
+		{"is-ca with path-len", []string{"--is-ca", "--path-len=2"}, false, true, 2, false},
+		{"is-ca with explicit path-len=0", []string{"--is-ca", "--path-len=0"}, false, true, 0, true},
+		{"path-len without is-ca", []string{"--path-len=1"}, true, false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTemplateTestContext(t, tt.args)
+			tmpl, err := buildCertTemplate(ctx, defaultLeafValidity)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("buildCertTemplate: expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildCertTemplate: unexpected error: %v", err)
+			}
+			if tmpl.IsCA != tt.wantIsCA {
+				t.Errorf("IsCA = %v, want %v", tmpl.IsCA, tt.wantIsCA)
+			}
+			if tmpl.MaxPathLen != tt.wantMaxPathLen {
+				t.Errorf("MaxPathLen = %d, want %d", tmpl.MaxPathLen, tt.wantMaxPathLen)
+			}
+			if tmpl.MaxPathLenZero != tt.wantPathLenZero {
+				t.Errorf("MaxPathLenZero = %v, want %v", tmpl.MaxPathLenZero, tt.wantPathLenZero)
+			}
+		})
+	}
+}
+
+func TestBuildCertTemplate_KeyUsage(t *testing.T) {
+	ctx := newTemplateTestContext(t, []string{
+		"--key-usage=digitalSignature,keyEncipherment",
+		"--ext-key-usage=serverAuth,clientAuth",
+		"--is-ca",
+	})
+	tmpl, err := buildCertTemplate(ctx, defaultLeafValidity)
+	if err != nil {
+		t.Fatalf("buildCertTemplate: unexpected error: %v", err)
+	}
+
+	want := x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign
+	if tmpl.KeyUsage != want {
+		t.Errorf("KeyUsage = %v, want %v (certSign implied by --is-ca)", tmpl.KeyUsage, want)
+	}
+	if len(tmpl.ExtKeyUsage) != 2 || tmpl.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth || tmpl.ExtKeyUsage[1] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("ExtKeyUsage = %v, want [serverAuth clientAuth]", tmpl.ExtKeyUsage)
+	}
+
+	if _, err := buildCertTemplate(newTemplateTestContext(t, []string{"--key-usage=bogus"}), defaultLeafValidity); err == nil {
+		t.Error("buildCertTemplate with an invalid --key-usage: expected an error, got none")
+	}
+	if _, err := buildCertTemplate(newTemplateTestContext(t, []string{"--ext-key-usage=bogus"}), defaultLeafValidity); err == nil {
+		t.Error("buildCertTemplate with an invalid --ext-key-usage: expected an error, got none")
+	}
+}
+
+func TestBuildCertTemplate_SANs(t *testing.T) {
+	ctx := newTemplateTestContext(t, []string{
+		"--dns=example.com", "--dns=www.example.com",
+		"--ip=127.0.0.1",
+		"--email=user@example.com",
+		"--uri=spiffe://example.com/service",
+	})
+	tmpl, err := buildCertTemplate(ctx, defaultLeafValidity)
+	if err != nil {
+		t.Fatalf("buildCertTemplate: unexpected error: %v", err)
+	}
+	if len(tmpl.DNSNames) != 2 || tmpl.DNSNames[0] != "example.com" || tmpl.DNSNames[1] != "www.example.com" {
+		t.Errorf("DNSNames = %v, want [example.com www.example.com]", tmpl.DNSNames)
+	}
+	if len(tmpl.IPAddresses) != 1 || tmpl.IPAddresses[0].String() != "127.0.0.1" {
+		t.Errorf("IPAddresses = %v, want [127.0.0.1]", tmpl.IPAddresses)
+	}
+	if len(tmpl.EmailAddresses) != 1 || tmpl.EmailAddresses[0] != "user@example.com" {
+		t.Errorf("EmailAddresses = %v, want [user@example.com]", tmpl.EmailAddresses)
+	}
+	if len(tmpl.URIs) != 1 || tmpl.URIs[0].String() != "spiffe://example.com/service" {
+		t.Errorf("URIs = %v, want [spiffe://example.com/service]", tmpl.URIs)
+	}
+
+	if _, err := buildCertTemplate(newTemplateTestContext(t, []string{"--ip=not-an-ip"}), defaultLeafValidity); err == nil {
+		t.Error("buildCertTemplate with an invalid --ip: expected an error, got none")
+	}
+	if _, err := buildCertTemplate(newTemplateTestContext(t, []string{"--uri=://bad"}), defaultLeafValidity); err == nil {
+		t.Error("buildCertTemplate with an invalid --uri: expected an error, got none")
+	}
+}
+
+func TestGenerateSubjectKey(t *testing.T) {
+	t.Run("no flags set uses package default", func(t *testing.T) {
+		if _, err := generateSubjectKey(newTemplateTestContext(t, nil)); err != nil {
+			t.Fatalf("generateSubjectKey: unexpected error: %v", err)
+		}
+	})
+
+	t.Run("kty=EC with curve", func(t *testing.T) {
+		key, err := generateSubjectKey(newTemplateTestContext(t, []string{"--kty=EC", "--curve=P-384"}))
+		if err != nil {
+			t.Fatalf("generateSubjectKey: unexpected error: %v", err)
+		}
+		if _, ok := key.(*ecdsa.PrivateKey); !ok {
+			t.Errorf("generateSubjectKey returned %T, want *ecdsa.PrivateKey", key)
+		}
+	})
+
+	t.Run("kty=RSA with size", func(t *testing.T) {
+		key, err := generateSubjectKey(newTemplateTestContext(t, []string{"--kty=RSA", "--size=2048"}))
+		if err != nil {
+			t.Fatalf("generateSubjectKey: unexpected error: %v", err)
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			t.Fatalf("generateSubjectKey returned %T, want *rsa.PrivateKey", key)
+		}
+		if rsaKey.N.BitLen() != 2048 {
+			t.Errorf("RSA key size = %d, want 2048", rsaKey.N.BitLen())
+		}
+	})
+
+	t.Run("kty=OKP ignores default curve/size", func(t *testing.T) {
+		key, err := generateSubjectKey(newTemplateTestContext(t, []string{"--kty=OKP"}))
+		if err != nil {
+			t.Fatalf("generateSubjectKey: unexpected error: %v", err)
+		}
+		if _, ok := key.(ed25519.PrivateKey); !ok {
+			t.Errorf("generateSubjectKey returned %T, want ed25519.PrivateKey", key)
+		}
+	})
+
+	t.Run("size without kty=RSA is an error", func(t *testing.T) {
+		if _, err := generateSubjectKey(newTemplateTestContext(t, []string{"--size=4096"})); err == nil {
+			t.Error("generateSubjectKey with --size and default --kty=EC: expected an error, got none")
+		}
+	})
+
+	t.Run("curve with kty=RSA is an error", func(t *testing.T) {
+		if _, err := generateSubjectKey(newTemplateTestContext(t, []string{"--kty=RSA", "--curve=P-521"})); err == nil {
+			t.Error("generateSubjectKey with --curve and --kty=RSA: expected an error, got none")
+		}
+	})
+
+	t.Run("unknown kty is an error", func(t *testing.T) {
+		if _, err := generateSubjectKey(newTemplateTestContext(t, []string{"--kty=DSA"})); err == nil {
+			t.Error("generateSubjectKey with an unknown --kty: expected an error, got none")
+		}
+	})
+}
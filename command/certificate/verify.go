@@ -1,14 +1,23 @@
 package certificate
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	stepx509 "github.com/smallstep/cli/crypto/certificates/x509"
 	"github.com/smallstep/cli/errs"
 	"github.com/urfave/cli"
+	"golang.org/x/crypto/ocsp"
 )
 
 func verifyCommand() cli.Command {
@@ -44,10 +53,231 @@ command will produce a non-zero return value.
         Relative or full path to a directory. Every PEM encoded certificate from each file
         in the directory will be used for path validation.`,
 			},
+			cli.BoolFlag{
+				Name: "crl",
+				Usage: `Check revocation status of the leaf and every intermediate using a
+Certificate Revocation List (CRL). The CRL is fetched from the URI found in
+the certificate's CRLDistributionPoints extension unless **--crl-file** is
+given.`,
+			},
+			cli.StringFlag{
+				Name: "crl-file",
+				Usage: `A file or URL from which to load the CRL instead of the URI
+embedded in the certificate. Requires **--crl**.`,
+			},
+			cli.BoolFlag{
+				Name: "ocsp",
+				Usage: `Check revocation status of the leaf and every intermediate using
+OCSP. The request is sent to the responder found in the certificate's
+OCSPServer extension.`,
+			},
+			cli.BoolFlag{
+				Name: "strict",
+				Usage: `Treat an "unknown" revocation response as a failure. Without this
+flag an unknown response is only a warning.`,
+			},
+			cli.BoolFlag{
+				Name: "no-network",
+				Usage: `Do not fetch CRLs or OCSP responses over the network; only consult
+the on-disk cache. Requires **--crl** or **--ocsp**.`,
+			},
+			cli.BoolFlag{
+				Name: "bundle",
+				Usage: `If the certificate's chain cannot be completed from **--roots** and the
+certificates in CRT_FILE, fetch the missing issuers using each certificate's
+Authority Information Access (AIA) IssuingCertificateURL and retry. Fetched
+issuers are cached under '~/.step/certs/aia'.`,
+			},
 		},
 	}
 }
 
+// revocationCacheDir returns the directory used to cache CRL and OCSP
+// responses, keyed by issuer and serial number.
+func revocationCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	dir := filepath.Join(home, ".step", "certs", "revocation")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return dir, nil
+}
+
+// revocationCacheKey returns a stable cache filename for a response covering
+// the given issuer/serial pair.
+func revocationCacheKey(kind string, issuer *x509.Certificate, serial interface{}) (string, error) {
+	dir, err := revocationCacheDir()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(append(issuer.RawSubjectPublicKeyInfo, []byte(fmt.Sprintf("%v", serial))...))
+	return filepath.Join(dir, fmt.Sprintf("%s-%s", kind, hex.EncodeToString(h[:]))), nil
+}
+
+func readCache(key string) ([]byte, bool) {
+	b, err := ioutil.ReadFile(key)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+func writeCache(key string, data []byte) {
+	_ = ioutil.WriteFile(key, data, 0600)
+}
+
+// checkCRL verifies that crt has not been revoked according to the CRL
+// issued by issuer. crlFile overrides the CRLDistributionPoints URI found on
+// crt, if non-empty.
+func checkCRL(crt, issuer *x509.Certificate, crlFile string, noNetwork bool) (bool, error) {
+	key, err := revocationCacheKey("crl", issuer, issuer.SerialNumber)
+	if err != nil {
+		return false, err
+	}
+
+	var der []byte
+	switch {
+	case noNetwork:
+		cached, ok := readCache(key)
+		if !ok {
+			return false, errors.Errorf("no cached CRL for issuer '%s' and --no-network was given", issuer.Subject.CommonName)
+		}
+		der = cached
+	case crlFile != "":
+		der, err = fetchCRL(crlFile)
+	case len(crt.CRLDistributionPoints) > 0:
+		der, err = fetchCRL(crt.CRLDistributionPoints[0])
+	default:
+		return false, errors.Errorf("certificate '%s' has no CRLDistributionPoints and no --crl-file was given", crt.Subject.CommonName)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	list, err := x509.ParseCRL(der)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to parse CRL")
+	}
+	if err := issuer.CheckCRLSignature(list); err != nil {
+		return false, errors.Wrap(err, "CRL signature does not verify against issuer")
+	}
+	if list.TBSCertList.NextUpdate.Before(time.Now()) {
+		return false, errors.Errorf("CRL for issuer '%s' has expired (nextUpdate %s)",
+			issuer.Subject.CommonName, list.TBSCertList.NextUpdate)
+	}
+	writeCache(key, der)
+
+	for _, revoked := range list.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(crt.SerialNumber) == 0 {
+			return false, errors.Errorf("certificate '%s' was revoked on %s", crt.Subject.CommonName, revoked.RevocationTime)
+		}
+	}
+	return true, nil
+}
+
+// fetchCRL loads a CRL in DER form from a local file path or an http(s) URL.
+func fetchCRL(uri string) ([]byte, error) {
+	if _, err := os.Stat(uri); err == nil {
+		return ioutil.ReadFile(uri)
+	}
+	res, err := http.Get(uri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch CRL from '%s'", uri)
+	}
+	defer res.Body.Close()
+	return ioutil.ReadAll(res.Body)
+}
+
+// checkOCSP verifies that crt has not been revoked according to the OCSP
+// responder advertised in its OCSPServer extension.
+func checkOCSP(crt, issuer *x509.Certificate, strict, noNetwork bool) (bool, error) {
+	key, err := revocationCacheKey("ocsp", issuer, crt.SerialNumber)
+	if err != nil {
+		return false, err
+	}
+
+	var respBytes []byte
+	if noNetwork {
+		cached, ok := readCache(key)
+		if !ok {
+			return false, errors.Errorf("no cached OCSP response for certificate '%s' and --no-network was given", crt.Subject.CommonName)
+		}
+		respBytes = cached
+	} else {
+		if len(crt.OCSPServer) == 0 {
+			return false, errors.Errorf("certificate '%s' has no OCSPServer", crt.Subject.CommonName)
+		}
+		reqBytes, err := ocsp.CreateRequest(crt, issuer, nil)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		res, err := http.Post(crt.OCSPServer[0], "application/ocsp-request", bytes.NewReader(reqBytes))
+		if err != nil {
+			return false, errors.Wrap(err, "failed to reach OCSP responder")
+		}
+		defer res.Body.Close()
+		respBytes, err = ioutil.ReadAll(res.Body)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		writeCache(key, respBytes)
+	}
+
+	resp, err := ocsp.ParseResponseForCert(respBytes, crt, issuer)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to parse OCSP response")
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		return true, nil
+	case ocsp.Revoked:
+		return false, errors.Errorf("certificate '%s' was revoked on %s", crt.Subject.CommonName, resp.RevokedAt)
+	default:
+		if strict {
+			return false, errors.Errorf("OCSP status for certificate '%s' is unknown", crt.Subject.CommonName)
+		}
+		fmt.Fprintf(os.Stderr, "warning: OCSP status for certificate '%s' is unknown\n", crt.Subject.CommonName)
+		return true, nil
+	}
+}
+
+// checkRevocation runs the configured CRL/OCSP checks against the leaf and
+// every intermediate in the chain, each verified against its issuer.
+func checkRevocation(ctx *cli.Context, chain []*x509.Certificate) error {
+	useCRL := ctx.Bool("crl")
+	useOCSP := ctx.Bool("ocsp")
+	if !useCRL && !useOCSP {
+		return nil
+	}
+	strict := ctx.Bool("strict")
+	crlFile := ctx.String("crl-file")
+	noNetwork := ctx.Bool("no-network")
+
+	for i, crt := range chain {
+		if i+1 >= len(chain) {
+			// The last certificate in the chain is the root; it is trusted
+			// directly and has no issuer to check revocation against.
+			break
+		}
+		issuer := chain[i+1]
+		if useCRL {
+			if ok, err := checkCRL(crt, issuer, crlFile, noNetwork); !ok {
+				return err
+			}
+		}
+		if useOCSP {
+			if ok, err := checkOCSP(crt, issuer, strict, noNetwork); !ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func verifyAction(ctx *cli.Context) error {
 	if err := errs.NumberOfArguments(ctx, 1); err != nil {
 		return err
@@ -108,9 +338,85 @@ func verifyAction(ctx *cli.Context) error {
 		Intermediates: intermediatePool,
 	}
 
-	if _, err := crt.Verify(opts); err != nil {
-		return errors.Wrapf(err, "failed to verify certificate")
+	chains, verifyErr := crt.Verify(opts)
+	if verifyErr != nil && ctx.Bool("bundle") {
+		if aiaErr := fetchAIAChain(crt, intermediatePool); aiaErr != nil {
+			return errors.Wrapf(aiaErr, "failed to fetch AIA issuer chain for certificate")
+		}
+		opts.Intermediates = intermediatePool
+		chains, verifyErr = crt.Verify(opts)
+	}
+	if verifyErr != nil {
+		return errors.Wrapf(verifyErr, "failed to verify certificate")
 	}
 
-	return nil
+	if ctx.Bool("no-network") && !ctx.Bool("crl") && !ctx.Bool("ocsp") {
+		return errors.Errorf("flag '--no-network' requires the '--crl' or '--ocsp' flag")
+	}
+
+	return checkRevocation(ctx, chains[0])
+}
+
+// fetchAIAChain walks crt's Authority Information Access
+// IssuingCertificateURL, downloading (and caching) each issuer in turn and
+// adding it to pool, until an issuer has no further AIA URL. It returns an
+// error if that takes more than maxChainDepth downloads.
+func fetchAIAChain(crt *x509.Certificate, pool *x509.CertPool) error {
+	const maxChainDepth = 10
+
+	current := crt
+	for i := 0; i < maxChainDepth; i++ {
+		if len(current.IssuingCertificateURL) == 0 {
+			return nil
+		}
+
+		der, err := fetchAIACert(current.IssuingCertificateURL[0])
+		if err != nil {
+			return err
+		}
+		issuer, err := x509.ParseCertificate(der)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse AIA issuer certificate")
+		}
+		pool.AddCert(issuer)
+		current = issuer
+	}
+	return errors.Errorf("AIA chain for certificate '%s' exceeded the maximum depth of %d",
+		crt.Subject.CommonName, maxChainDepth)
+}
+
+// fetchAIACert downloads (or loads from cache) the issuer certificate at
+// uri, caching it under ~/.step/certs/aia/<sha256 of uri>.pem.
+func fetchAIACert(uri string) ([]byte, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dir := filepath.Join(home, ".step", "certs", "aia")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	h := sha256.Sum256([]byte(uri))
+	key := filepath.Join(dir, hex.EncodeToString(h[:])+".pem")
+
+	if cached, ok := readCache(key); ok {
+		return cached, nil
+	}
+
+	res, err := http.Get(uri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch issuer certificate from '%s'", uri)
+	}
+	defer res.Body.Close()
+	der, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// AIA issuer certificates are most commonly DER, but some CAs serve PEM.
+	if block, _ := pem.Decode(der); block != nil {
+		der = block.Bytes
+	}
+
+	writeCache(key, der)
+	return der, nil
 }
@@ -0,0 +1,226 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testIssuerAndLeaf returns a self-signed CA certificate and a leaf
+// certificate it issued, along with the CA's private key.
+func testIssuerAndLeaf(t *testing.T) (issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey, leaf *x509.Certificate) {
+	t.Helper()
+
+	issuerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate issuer key: %v", err)
+	}
+	issuerTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	issuerDER, err := x509.CreateCertificate(rand.Reader, issuerTmpl, issuerTmpl, &issuerKey.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("failed to create issuer certificate: %v", err)
+	}
+	issuer, err = x509.ParseCertificate(issuerDER)
+	if err != nil {
+		t.Fatalf("failed to parse issuer certificate: %v", err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate leaf key: %v", err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, issuer, &leafKey.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("failed to create leaf certificate: %v", err)
+	}
+	leaf, err = x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("failed to parse leaf certificate: %v", err)
+	}
+	return issuer, issuerKey, leaf
+}
+
+func writeCRLFile(t *testing.T, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey,
+	revoked []pkix.RevokedCertificate, nextUpdate time.Time) string {
+	t.Helper()
+
+	der, err := issuer.CreateCRL(rand.Reader, issuerKey, revoked, time.Now(), nextUpdate)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "test.crl")
+	if err := ioutil.WriteFile(path, der, 0600); err != nil {
+		t.Fatalf("failed to write CRL file: %v", err)
+	}
+	return path
+}
+
+func TestCheckCRL_NotRevoked(t *testing.T) {
+	issuer, issuerKey, leaf := testIssuerAndLeaf(t)
+	crlFile := writeCRLFile(t, issuer, issuerKey, nil, time.Now().Add(time.Hour))
+
+	ok, err := checkCRL(leaf, issuer, crlFile, false)
+	if err != nil {
+		t.Fatalf("checkCRL: unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("checkCRL: expected certificate to be valid, got revoked")
+	}
+}
+
+func TestCheckCRL_Revoked(t *testing.T) {
+	issuer, issuerKey, leaf := testIssuerAndLeaf(t)
+	revoked := []pkix.RevokedCertificate{
+		{SerialNumber: leaf.SerialNumber, RevocationTime: time.Now().Add(-time.Minute)},
+	}
+	crlFile := writeCRLFile(t, issuer, issuerKey, revoked, time.Now().Add(time.Hour))
+
+	ok, err := checkCRL(leaf, issuer, crlFile, false)
+	if err == nil || ok {
+		t.Fatalf("checkCRL: expected a revocation error, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestCheckCRL_Expired(t *testing.T) {
+	issuer, issuerKey, leaf := testIssuerAndLeaf(t)
+	crlFile := writeCRLFile(t, issuer, issuerKey, nil, time.Now().Add(-time.Minute))
+
+	ok, err := checkCRL(leaf, issuer, crlFile, false)
+	if err == nil || ok {
+		t.Fatalf("checkCRL: expected an expired-CRL error, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestCheckOCSP(t *testing.T) {
+	issuer, issuerKey, leaf := testIssuerAndLeaf(t)
+
+	tests := []struct {
+		name    string
+		status  int
+		wantOK  bool
+		wantErr bool
+	}{
+		{"good", ocsp.Good, true, false},
+		{"revoked", ocsp.Revoked, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			respBytes, err := ocsp.CreateResponse(issuer, issuer, ocsp.Response{
+				Status:       tt.status,
+				SerialNumber: leaf.SerialNumber,
+				ThisUpdate:   time.Now().Add(-time.Minute),
+				NextUpdate:   time.Now().Add(time.Hour),
+				RevokedAt:    time.Now().Add(-time.Minute),
+			}, issuerKey)
+			if err != nil {
+				t.Fatalf("failed to create OCSP response: %v", err)
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(respBytes)
+			}))
+			defer srv.Close()
+			leaf.OCSPServer = []string{srv.URL}
+
+			ok, err := checkOCSP(leaf, issuer, true, false)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("checkOCSP: expected an error, got none (ok=%v)", ok)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkOCSP: unexpected error: %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("checkOCSP: ok = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckOCSP_UnknownStrictVsWarn(t *testing.T) {
+	issuer, issuerKey, leaf := testIssuerAndLeaf(t)
+	respBytes, err := ocsp.CreateResponse(issuer, issuer, ocsp.Response{
+		Status:       ocsp.Unknown,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}, issuerKey)
+	if err != nil {
+		t.Fatalf("failed to create OCSP response: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(respBytes)
+	}))
+	defer srv.Close()
+	leaf.OCSPServer = []string{srv.URL}
+
+	if _, err := checkOCSP(leaf, issuer, true, false); err == nil {
+		t.Error("checkOCSP with --strict and an unknown status: expected an error, got none")
+	}
+
+	leaf.OCSPServer = []string{srv.URL}
+	ok, err := checkOCSP(leaf, issuer, false, false)
+	if err != nil {
+		t.Fatalf("checkOCSP without --strict: unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("checkOCSP without --strict and an unknown status: expected ok=true (a warning, not a failure)")
+	}
+}
+
+func TestRevocationCacheKeyStable(t *testing.T) {
+	issuer, _, leaf := testIssuerAndLeaf(t)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	k1, err := revocationCacheKey("crl", issuer, leaf.SerialNumber)
+	if err != nil {
+		t.Fatalf("revocationCacheKey: unexpected error: %v", err)
+	}
+	k2, err := revocationCacheKey("crl", issuer, leaf.SerialNumber)
+	if err != nil {
+		t.Fatalf("revocationCacheKey: unexpected error: %v", err)
+	}
+	if k1 != k2 {
+		t.Errorf("revocationCacheKey is not stable: %q != %q", k1, k2)
+	}
+
+	writeCache(k1, []byte("cached"))
+	got, ok := readCache(k1)
+	if !ok || string(got) != "cached" {
+		t.Errorf("readCache after writeCache = (%q, %v), want (%q, true)", got, ok, "cached")
+	}
+	if _, err := os.Stat(k1); err != nil {
+		t.Errorf("expected cache file at %q: %v", k1, err)
+	}
+}
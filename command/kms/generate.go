@@ -0,0 +1,61 @@
+package kms
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/crypto/kms"
+	"github.com/smallstep/cli/errs"
+	"github.com/urfave/cli"
+)
+
+func generateCommand() cli.Command {
+	return cli.Command{
+		Name:      "generate",
+		Action:    cli.ActionFunc(generateAction),
+		Usage:     `pre-provision a key in a KMS slot or token.`,
+		UsageText: `step kms generate <uri> [**--kty**=<key-type>] [**--curve**=<curve>] [**--size**=<size>]`,
+		Description: `**step kms generate** creates a new key inside the KMS at <uri> and
+prints the reference URI to use with 'step certificate create --kms' or
+**--ca-kms**. No key material ever leaves the KMS.
+
+  POSITIONAL ARGUMENTS
+    <uri>
+      A KMS URI identifying the slot or token, and optionally a label for
+      the new key (e.g. 'pkcs11:token=YubiKey#1;object=intermediate-ca').`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "kty",
+				Value: "EC",
+				Usage: `The key type to generate. Must be one of: **EC**, **RSA**.`,
+			},
+			cli.StringFlag{
+				Name:  "curve",
+				Value: "P-256",
+				Usage: `The elliptic curve to use with **--kty**=EC. Must be one of: **P-256**, **P-384**, **P-521**.`,
+			},
+			cli.IntFlag{
+				Name:  "size",
+				Value: 2048,
+				Usage: `The key size, in bits, to use with **--kty**=RSA.`,
+			},
+		},
+	}
+}
+
+func generateAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+	uri := ctx.Args().Get(0)
+	if !validKMSURI(uri) {
+		return errors.Errorf("'%s' is not a valid KMS URI; expected the form '<scheme>:<opaque>', e.g. 'pkcs11:token=YubiKey#1'", uri)
+	}
+
+	ref, err := kms.GenerateNamedKey(uri, ctx.String("kty"), ctx.String("curve"), ctx.Int("size"))
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate a key at '%s'", uri)
+	}
+	fmt.Println(ref)
+	return nil
+}
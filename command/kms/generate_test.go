@@ -0,0 +1,15 @@
+package kms
+
+import "testing"
+
+func TestGenerateAction_MissingURI(t *testing.T) {
+	if err := generateAction(newKMSTestContext(t, nil)); err == nil {
+		t.Error("generateAction with no arguments: expected an error, got none")
+	}
+}
+
+func TestGenerateAction_MalformedURI(t *testing.T) {
+	if err := generateAction(newKMSTestContext(t, []string{"not-a-kms-uri"})); err == nil {
+		t.Error("generateAction with a malformed KMS URI: expected an error, got none")
+	}
+}
@@ -0,0 +1,35 @@
+// Package kms implements CLI commands for enumerating and pre-provisioning
+// keys held in a hardware or cloud key management service, as used by
+// 'step certificate create --kms=...'.
+package kms
+
+import (
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// Command returns the 'kms' command and its subcommands.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "kms",
+		Usage:     "manage keys held in a hardware or cloud key management service",
+		UsageText: "step kms <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `**step kms** command group provides facilities for enumerating and
+pre-provisioning keys in a KMS (PKCS #11 token, YubiHSM, or similar) ahead
+of using them with 'step certificate create --kms=<uri>'.`,
+		Subcommands: cli.Commands{
+			listCommand(),
+			generateCommand(),
+		},
+	}
+}
+
+// validKMSURI reports whether uri has the '<scheme>:<opaque>' shape shared
+// by every supported KMS URI (RFC 7512 PKCS #11 URIs, 'yubihsm:', etc.),
+// without attempting to validate the scheme itself; that is left to the
+// underlying kms package.
+func validKMSURI(uri string) bool {
+	i := strings.Index(uri, ":")
+	return i > 0 && i < len(uri)-1
+}
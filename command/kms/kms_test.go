@@ -0,0 +1,22 @@
+package kms
+
+import "testing"
+
+func TestValidKMSURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"pkcs11:module-path=/usr/lib/libykcs11.so;token=YubiKey#1", true},
+		{"yubihsm:slot-id=1", true},
+		{"", false},
+		{"no-scheme-here", false},
+		{":missing-scheme", false},
+		{"scheme:", false},
+	}
+	for _, tt := range tests {
+		if got := validKMSURI(tt.uri); got != tt.want {
+			t.Errorf("validKMSURI(%q) = %v, want %v", tt.uri, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package kms
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/crypto/kms"
+	"github.com/smallstep/cli/errs"
+	"github.com/urfave/cli"
+)
+
+func listCommand() cli.Command {
+	return cli.Command{
+		Name:      "list",
+		Action:    cli.ActionFunc(listAction),
+		Usage:     `list the keys available in a KMS slot or token.`,
+		UsageText: `step kms list <uri>`,
+		Description: `**step kms list** enumerates the keys visible at <uri> and prints, for
+each, its label, key type, and full reference URI (the value to pass as
+**--kms** or **--ca-kms** to 'step certificate create').
+
+  POSITIONAL ARGUMENTS
+    <uri>
+      A KMS URI identifying a slot or token, following the RFC 7512
+      PKCS #11 URI form, e.g. 'pkcs11:module-path=/usr/lib/libykcs11.so;token=YubiKey#1'.`,
+	}
+}
+
+func listAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+	uri := ctx.Args().Get(0)
+	if !validKMSURI(uri) {
+		return errors.Errorf("'%s' is not a valid KMS URI; expected the form '<scheme>:<opaque>', e.g. 'pkcs11:token=YubiKey#1'", uri)
+	}
+
+	keys, err := kms.ListKeys(uri)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list keys at '%s'", uri)
+	}
+	for _, k := range keys {
+		fmt.Printf("%s\t%s\t%s\n", k.Label, k.Type, k.URI)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package kms
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func newKMSTestContext(t *testing.T, args []string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("failed to parse args %v: %v", args, err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestListAction_MissingURI(t *testing.T) {
+	if err := listAction(newKMSTestContext(t, nil)); err == nil {
+		t.Error("listAction with no arguments: expected an error, got none")
+	}
+}
+
+func TestListAction_MalformedURI(t *testing.T) {
+	if err := listAction(newKMSTestContext(t, []string{"not-a-kms-uri"})); err == nil {
+		t.Error("listAction with a malformed KMS URI: expected an error, got none")
+	}
+}
@@ -0,0 +1,22 @@
+// Package ssh implements CLI commands for verifying SSH certificates, as a
+// companion to the x.509 tooling in the certificate package, which creates
+// them.
+package ssh
+
+import (
+	"github.com/urfave/cli"
+)
+
+// Command returns the 'ssh' command and its subcommands.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "ssh",
+		Usage:     "verify SSH certificates",
+		UsageText: "step ssh <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `**step ssh** command group provides facilities for validating SSH
+certificates. To create one, use 'step certificate create --type=ssh'.`,
+		Subcommands: cli.Commands{
+			verifyCommand(),
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package ssh
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/cli/errs"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh"
+)
+
+func verifyCommand() cli.Command {
+	return cli.Command{
+		Name:      "verify",
+		Action:    cli.ActionFunc(verifyAction),
+		Usage:     `verify an SSH certificate.`,
+		UsageText: `step ssh verify CRT_FILE --ca=CA_FILE [--principal=PRINCIPAL]`,
+		Description: `**step ssh verify** parses an authorized-keys formatted SSH certificate,
+checks that it was signed by a trusted certificate authority, and that it is
+currently within its validity window. If **--principal** is given it also
+checks that the principal is allowed by the certificate.
+
+  POSITIONAL ARGUMENTS
+    CRT_FILE
+      The path to an SSH certificate, in 'ssh-*-cert-v01@openssh.com'
+      authorized-keys format.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "ca",
+				Usage: `The trusted certificate authority public key, in authorized-keys format.`,
+			},
+			cli.StringFlag{
+				Name: "principal",
+				Usage: `Check that the certificate is valid for the given principal. Required
+if the certificate lists any valid principals.`,
+			},
+		},
+	}
+}
+
+func verifyAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	crtFile := ctx.Args().Get(0)
+	crtBytes, err := ioutil.ReadFile(crtFile)
+	if err != nil {
+		return errs.FileError(err, crtFile)
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(crtBytes)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse SSH certificate '%s'", crtFile)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return errors.Errorf("'%s' is an SSH public key, not a certificate", crtFile)
+	}
+
+	caFile := ctx.String("ca")
+	if caFile == "" {
+		return errs.RequiredFlag(ctx, "ca")
+	}
+	caBytes, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return errs.FileError(err, caFile)
+	}
+	caPub, _, _, _, err := ssh.ParseAuthorizedKey(caBytes)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse CA public key '%s'", caFile)
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return sshKeysEqual(auth, caPub)
+		},
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			return sshKeysEqual(auth, caPub)
+		},
+	}
+
+	var trusted bool
+	switch cert.CertType {
+	case ssh.UserCert:
+		trusted = checker.IsUserAuthority(cert.SignatureKey)
+	case ssh.HostCert:
+		trusted = checker.IsHostAuthority(cert.SignatureKey, "")
+	}
+	if !trusted {
+		return errors.Errorf("certificate '%s' was not signed by the trusted CA in '%s'", crtFile, caFile)
+	}
+
+	if err := checker.CheckCert(ctx.String("principal"), cert); err != nil {
+		return errors.Wrapf(err, "certificate '%s' failed validation", crtFile)
+	}
+
+	return nil
+}
+
+// sshKeysEqual reports whether two SSH public keys are the same key.
+func sshKeysEqual(a, b ssh.PublicKey) bool {
+	return string(a.Marshal()) == string(b.Marshal())
+}